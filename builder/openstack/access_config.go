@@ -4,13 +4,16 @@ import (
 	"crypto/tls"
 	"crypto/x509"
 	"fmt"
-	"io/ioutil"
 	"os"
 
 	"github.com/gophercloud/gophercloud"
 	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/extensions/trusts"
+	tokens3 "github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
+	"github.com/gophercloud/gophercloud/openstack/objectstorage/v1/swauth"
 	"github.com/gophercloud/utils/openstack/clientconfig"
 	"github.com/hashicorp/go-cleanhttp"
+	"github.com/hashicorp/packer/helper/pathorcontents"
 	"github.com/hashicorp/packer/template/interpolate"
 )
 
@@ -28,10 +31,29 @@ type AccessConfig struct {
 	Region           string `mapstructure:"region"`
 	EndpointType     string `mapstructure:"endpoint_type"`
 	CACertFile       string `mapstructure:"cacert"`
+	CACertDirectory  string `mapstructure:"cacert_dir"`
 	ClientCertFile   string `mapstructure:"cert"`
 	ClientKeyFile    string `mapstructure:"key"`
 	Token            string `mapstructure:"token"`
 	Cloud            string `mapstructure:"cloud"`
+	TrustID          string `mapstructure:"trust_id"`
+	Swauth           bool   `mapstructure:"swauth"`
+
+	ApplicationCredentialID     string `mapstructure:"application_credential_id"`
+	ApplicationCredentialName   string `mapstructure:"application_credential_name"`
+	ApplicationCredentialSecret string `mapstructure:"application_credential_secret"`
+
+	// TokenCachePath overrides where the acquired token is cached between
+	// builder runs. Defaults to ~/.cache/packer/openstack-token-<hash>.json.
+	// Set to "-" to disable caching entirely.
+	TokenCachePath string `mapstructure:"token_cache_path"`
+
+	// MaxRetries, RetryMaxWaitSeconds and RateLimit tune the HTTP
+	// middleware wrapped around every request made to Keystone/Nova/etc.
+	// See wrapTransport.
+	MaxRetries          int     `mapstructure:"max_retries"`
+	RetryMaxWaitSeconds int     `mapstructure:"retry_max_wait_seconds"`
+	RateLimit           float64 `mapstructure:"rate_limit"`
 
 	osClient *gophercloud.ProviderClient
 }
@@ -76,6 +98,20 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 		c.ClientKeyFile = os.Getenv("OS_KEY")
 	}
 
+	if c.TrustID == "" {
+		c.TrustID = os.Getenv("OS_TRUST_ID")
+	}
+
+	if c.ApplicationCredentialID == "" {
+		c.ApplicationCredentialID = os.Getenv("OS_APPLICATION_CREDENTIAL_ID")
+	}
+	if c.ApplicationCredentialName == "" {
+		c.ApplicationCredentialName = os.Getenv("OS_APPLICATION_CREDENTIAL_NAME")
+	}
+	if c.ApplicationCredentialSecret == "" {
+		c.ApplicationCredentialSecret = os.Getenv("OS_APPLICATION_CREDENTIAL_SECRET")
+	}
+
 	clientOpts := new(clientconfig.ClientOpts)
 
 	// If a cloud entry was given, base AuthOptions on a clouds.yaml file.
@@ -90,67 +126,107 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 		if c.Region == "" && cloud.RegionName != "" {
 			c.Region = cloud.RegionName
 		}
+
+		// clientconfig has no first-class notion of swauth; v1password is
+		// its clouds.yaml spelling, so match the raw auth_type string.
+		// v2password and v3applicationcredential both go through the
+		// normal Keystone path.
+		if string(cloud.AuthType) == "v1password" {
+			c.Swauth = true
+			c.IdentityEndpoint = cloud.AuthInfo.AuthURL
+			c.Username = cloud.AuthInfo.Username
+			c.Password = cloud.AuthInfo.Password
+		}
 	} else {
 		authInfo := &clientconfig.AuthInfo{
-			AuthURL:     c.IdentityEndpoint,
-			DomainID:    c.DomainID,
-			DomainName:  c.DomainName,
-			Password:    c.Password,
-			ProjectID:   c.TenantID,
-			ProjectName: c.TenantName,
-			Token:       c.Token,
-			Username:    c.Username,
-			UserID:      c.UserID,
+			AuthURL:                     c.IdentityEndpoint,
+			DomainID:                    c.DomainID,
+			DomainName:                  c.DomainName,
+			Password:                    c.Password,
+			ProjectID:                   c.TenantID,
+			ProjectName:                 c.TenantName,
+			Token:                       c.Token,
+			Username:                    c.Username,
+			UserID:                      c.UserID,
+			ApplicationCredentialID:     c.ApplicationCredentialID,
+			ApplicationCredentialName:   c.ApplicationCredentialName,
+			ApplicationCredentialSecret: c.ApplicationCredentialSecret,
 		}
 		clientOpts.AuthInfo = authInfo
 	}
 
-	ao, err := clientconfig.AuthOptions(clientOpts)
-	if err != nil {
-		return []error{err}
-	}
+	// swauth has no notion of Keystone AuthOptions; it authenticates
+	// against the Swift proxy, when ObjectStorageV1Client builds the Swift
+	// client, with the identity endpoint, username and password (key) we
+	// already pulled out of clouds.yaml or the config above.
+	var ao *gophercloud.AuthOptions
+	var err error
+	identityEndpoint := c.IdentityEndpoint
+	if !c.Swauth {
+		ao, err = clientconfig.AuthOptions(clientOpts)
+		if err != nil {
+			return []error{err}
+		}
 
-	// Make sure we reauth as needed
-	ao.AllowReauth = true
+		// Make sure we reauth as needed
+		ao.AllowReauth = true
 
-	// Override values if we have them in our config
-	overrides := []struct {
-		From, To *string
-	}{
-		{&c.Username, &ao.Username},
-		{&c.UserID, &ao.UserID},
-		{&c.Password, &ao.Password},
-		{&c.IdentityEndpoint, &ao.IdentityEndpoint},
-		{&c.TenantID, &ao.TenantID},
-		{&c.TenantName, &ao.TenantName},
-		{&c.DomainID, &ao.DomainID},
-		{&c.DomainName, &ao.DomainName},
-		{&c.Token, &ao.TokenID},
-	}
-	for _, s := range overrides {
-		if *s.From != "" {
-			*s.To = *s.From
+		// Override values if we have them in our config
+		overrides := []struct {
+			From, To *string
+		}{
+			{&c.Username, &ao.Username},
+			{&c.UserID, &ao.UserID},
+			{&c.Password, &ao.Password},
+			{&c.IdentityEndpoint, &ao.IdentityEndpoint},
+			{&c.TenantID, &ao.TenantID},
+			{&c.TenantName, &ao.TenantName},
+			{&c.DomainID, &ao.DomainID},
+			{&c.DomainName, &ao.DomainName},
+			{&c.Token, &ao.TokenID},
 		}
+		for _, s := range overrides {
+			if *s.From != "" {
+				*s.To = *s.From
+			}
+		}
+
+		identityEndpoint = ao.IdentityEndpoint
 	}
 
 	// Build the client itself
-	client, err := openstack.NewClient(ao.IdentityEndpoint)
+	client, err := openstack.NewClient(identityEndpoint)
 	if err != nil {
 		return []error{err}
 	}
 
 	tls_config := &tls.Config{}
 
+	// Start from the system trust store and layer the user-supplied CA(s)
+	// on top, rather than replacing it outright. Keystone fronted by a
+	// public CA and Glance/Nova fronted by a private one is a common split,
+	// and a pool with only the user CA breaks that mix.
+	caCertPool, err := x509.SystemCertPool()
+	if err != nil || caCertPool == nil {
+		caCertPool = x509.NewCertPool()
+	}
+
 	if c.CACertFile != "" {
-		caCert, err := ioutil.ReadFile(c.CACertFile)
+		caCert, _, err := pathorcontents.Read(c.CACertFile)
 		if err != nil {
+			return []error{fmt.Errorf("Error reading CA Cert: %s", err)}
+		}
+		caCertPool.AppendCertsFromPEM([]byte(caCert))
+	}
+
+	if c.CACertDirectory != "" {
+		if err := appendCACertsFromDir(caCertPool, c.CACertDirectory); err != nil {
 			return []error{err}
 		}
-		caCertPool := x509.NewCertPool()
-		caCertPool.AppendCertsFromPEM(caCert)
-		tls_config.RootCAs = caCertPool
 	}
 
+	tls_config.RootCAs = caCertPool
+
 	// If we have insecure set, then create a custom HTTP client that
 	// ignores SSL errors.
 	if c.Insecure {
@@ -158,7 +234,15 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 	}
 
 	if c.ClientCertFile != "" && c.ClientKeyFile != "" {
-		cert, err := tls.LoadX509KeyPair(c.ClientCertFile, c.ClientKeyFile)
+		clientCert, _, err := pathorcontents.Read(c.ClientCertFile)
+		if err != nil {
+			return []error{fmt.Errorf("Error reading Client Cert: %s", err)}
+		}
+		clientKey, _, err := pathorcontents.Read(c.ClientKeyFile)
+		if err != nil {
+			return []error{fmt.Errorf("Error reading Client Key: %s", err)}
+		}
+		cert, err := tls.X509KeyPair([]byte(clientCert), []byte(clientKey))
 		if err != nil {
 			return []error{err}
 		}
@@ -168,12 +252,72 @@ func (c *AccessConfig) Prepare(ctx *interpolate.Context) []error {
 
 	transport := cleanhttp.DefaultTransport()
 	transport.TLSClientConfig = tls_config
-	client.HTTPClient.Transport = transport
+	client.HTTPClient.Transport = c.wrapTransport(transport)
 
-	// Auth
-	err = openstack.Authenticate(client, *ao)
-	if err != nil {
-		return []error{err}
+	// authenticate performs a full Keystone auth: the normal path (v2 or
+	// v3, as openstack.Authenticate picks from the identity endpoint), or,
+	// if a trust_id was provided, authentication through the Keystone v3
+	// trust extension so Packer can run under a delegated trust token
+	// (e.g. minted by Magnum/Heat) rather than a stored username/password.
+	authenticate := func() error {
+		if c.TrustID != "" {
+			client.IdentityBase = ao.IdentityEndpoint
+			authOptsExt := trusts.AuthOptsExt{
+				AuthOptionsBuilder: ao,
+				TrustID:            c.TrustID,
+			}
+			return openstack.AuthenticateV3(client, authOptsExt, gophercloud.EndpointOpts{})
+		}
+		return openstack.Authenticate(client, *ao)
+	}
+
+	// Legacy Swift auth (swauth) authenticates against the Swift proxy
+	// itself when ObjectStorageV1Client is called, so there's no Keystone
+	// token to acquire here.
+	var cached *cachedToken
+	if !c.Swauth {
+		cached, err = c.loadCachedToken(ao)
+		if err != nil {
+			return []error{err}
+		}
+	}
+
+	switch {
+	case c.Swauth:
+		// nothing to do; ObjectStorageV1Client() authenticates via swauth.
+	case cached != nil:
+		// A valid cached token exists: skip the Keystone round-trip
+		// entirely and hydrate the client directly from the cache.
+		catalog := tokens3.ServiceCatalog{Entries: cached.Catalog}
+		client.TokenID = cached.TokenID
+		client.EndpointLocator = func(opts gophercloud.EndpointOpts) (string, error) {
+			return openstack.V3EndpointURL(&catalog, opts)
+		}
+		client.ReauthFunc = func() error {
+			if err := authenticate(); err != nil {
+				return err
+			}
+			return c.saveCachedToken(client, ao)
+		}
+	default:
+		if err = authenticate(); err != nil {
+			return []error{err}
+		}
+		if err := c.saveCachedToken(client, ao); err != nil {
+			return []error{err}
+		}
+
+		// Transparently refresh and rewrite the cache whenever the normal
+		// reauth logic kicks in on a 401.
+		origReauth := client.ReauthFunc
+		client.ReauthFunc = func() error {
+			if origReauth != nil {
+				if err := origReauth(); err != nil {
+					return err
+				}
+			}
+			return c.saveCachedToken(client, ao)
+		}
 	}
 
 	c.osClient = client
@@ -201,6 +345,26 @@ func (c *AccessConfig) blockStorageV3Client() (*gophercloud.ServiceClient, error
 	})
 }
 
+// ObjectStorageV1Client returns a Swift client, authenticated either through
+// the normal Keystone-backed provider client or, when Swauth is set,
+// through legacy Swift (TempAuth) credentials.
+func (c *AccessConfig) ObjectStorageV1Client() (*gophercloud.ServiceClient, error) {
+	if c.Swauth {
+		// swauth derives the auth URL from IdentityBase (appending
+		// "auth/v1.0"), which openstack.NewClient already set from
+		// IdentityEndpoint when building c.osClient.
+		return swauth.NewObjectStorageV1(c.osClient, swauth.AuthOpts{
+			User: c.Username,
+			Key:  c.Password,
+		})
+	}
+
+	return openstack.NewObjectStorageV1(c.osClient, gophercloud.EndpointOpts{
+		Region:       c.Region,
+		Availability: c.getEndpointType(),
+	})
+}
+
 func (c *AccessConfig) getEndpointType() gophercloud.Availability {
 	if c.EndpointType == "internal" || c.EndpointType == "internalURL" {
 		return gophercloud.AvailabilityInternal
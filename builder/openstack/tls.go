@@ -0,0 +1,40 @@
+package openstack
+
+import (
+	"crypto/x509"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// appendCACertsFromDir walks dir and appends every .pem or .crt file it
+// finds to pool (files with other extensions are skipped), so that cacert_dir can point at something like a system CA
+// directory (e.g. /etc/ssl/certs) in addition to the single cacert file.
+func appendCACertsFromDir(pool *x509.CertPool, dir string) error {
+	return filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		ext := strings.ToLower(filepath.Ext(path))
+		if ext != ".pem" && ext != ".crt" {
+			return nil
+		}
+
+		contents, err := ioutil.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("error reading %s: %s", path, err)
+		}
+
+		if !pool.AppendCertsFromPEM(contents) {
+			return fmt.Errorf("no certificates found in %s", path)
+		}
+
+		return nil
+	})
+}
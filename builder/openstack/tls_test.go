@@ -0,0 +1,88 @@
+package openstack
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io/ioutil"
+	"math/big"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// generateTestPEMCert returns a throwaway self-signed certificate PEM block
+// for exercising appendCACertsFromDir without relying on fixture files on
+// disk.
+func generateTestPEMCert(t *testing.T, commonName string) []byte {
+	t.Helper()
+
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("error generating key: %s", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: big.NewInt(1),
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    time.Unix(0, 0),
+		NotAfter:     time.Unix(0, 0).Add(24 * time.Hour),
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("error creating certificate: %s", err)
+	}
+
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+}
+
+func TestAppendCACertsFromDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-openstack-cacert-dir")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	certA := generateTestPEMCert(t, "a.example.com")
+	certB := generateTestPEMCert(t, "b.example.com")
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "a.pem"), certA, 0644); err != nil {
+		t.Fatalf("error writing a.pem: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "b.crt"), certB, 0644); err != nil {
+		t.Fatalf("error writing b.crt: %s", err)
+	}
+	if err := ioutil.WriteFile(filepath.Join(dir, "readme.txt"), []byte("not a cert"), 0644); err != nil {
+		t.Fatalf("error writing readme.txt: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+	if err := appendCACertsFromDir(pool, dir); err != nil {
+		t.Fatalf("appendCACertsFromDir returned an error: %s", err)
+	}
+
+	if got, want := len(pool.Subjects()), 2; got != want {
+		t.Fatalf("expected %d certs in the pool, got %d", want, got)
+	}
+}
+
+func TestAppendCACertsFromDir_invalidPEM(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-openstack-cacert-dir-invalid")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	if err := ioutil.WriteFile(filepath.Join(dir, "bad.pem"), []byte("not a pem file"), 0644); err != nil {
+		t.Fatalf("error writing bad.pem: %s", err)
+	}
+
+	pool := x509.NewCertPool()
+	if err := appendCACertsFromDir(pool, dir); err == nil {
+		t.Fatal("expected an error for a .pem file with no certificates, got nil")
+	}
+}
@@ -0,0 +1,127 @@
+package openstack
+
+import (
+	"crypto/sha256"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack"
+	"github.com/gophercloud/gophercloud/openstack/identity/v3/tokens"
+	"github.com/mitchellh/go-homedir"
+)
+
+// cachedToken is the on-disk representation of a previously acquired
+// Keystone token, persisted so that repeated `packer build` invocations
+// against the same cloud can reuse it instead of re-authenticating.
+type cachedToken struct {
+	TokenID   string                `json:"token_id"`
+	Catalog   []tokens.CatalogEntry `json:"catalog"`
+	ExpiresAt time.Time             `json:"expires_at"`
+}
+
+// tokenCachePath resolves the on-disk location of the token cache for ao.
+// It defaults to ~/.cache/packer/openstack-token-<hash>.json, where the
+// hash is derived from the identity endpoint and principal being
+// authenticated so that distinct clouds/users don't collide.
+func (c *AccessConfig) tokenCachePath(ao *gophercloud.AuthOptions) (string, error) {
+	if c.TokenCachePath == "-" {
+		return "", nil
+	}
+	if c.TokenCachePath != "" {
+		return c.TokenCachePath, nil
+	}
+
+	home, err := homedir.Dir()
+	if err != nil {
+		return "", fmt.Errorf("error finding home directory for token cache: %s", err)
+	}
+
+	h := sha256.New()
+	fmt.Fprintf(h, "%s\x00%s\x00%s\x00%s\x00%s\x00%s\x00%s",
+		ao.IdentityEndpoint, ao.Username, ao.UserID, ao.TenantID, ao.TenantName,
+		ao.ApplicationCredentialID, ao.ApplicationCredentialName)
+
+	return filepath.Join(home, ".cache", "packer", fmt.Sprintf("openstack-token-%x.json", h.Sum(nil))), nil
+}
+
+// loadCachedToken returns the cached token for ao, or nil if there is no
+// cache, the cache can't be read, or the cached token has expired.
+func (c *AccessConfig) loadCachedToken(ao *gophercloud.AuthOptions) (*cachedToken, error) {
+	path, err := c.tokenCachePath(ao)
+	if err != nil {
+		return nil, err
+	}
+	if path == "" {
+		return nil, nil
+	}
+
+	raw, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, nil
+	}
+
+	var cached cachedToken
+	if err := json.Unmarshal(raw, &cached); err != nil {
+		return nil, nil
+	}
+
+	if cached.TokenID == "" || !time.Now().Before(cached.ExpiresAt) {
+		return nil, nil
+	}
+
+	return &cached, nil
+}
+
+// saveCachedToken writes client's current token, catalog and expiry to the
+// configured cache path, creating the parent directory if needed. ao must
+// be the same AuthOptions used to look up the cache in loadCachedToken, so
+// that the two hash to the same path.
+func (c *AccessConfig) saveCachedToken(client *gophercloud.ProviderClient, ao *gophercloud.AuthOptions) error {
+	path, err := c.tokenCachePath(ao)
+	if err != nil {
+		return err
+	}
+	if path == "" {
+		return nil
+	}
+
+	identity, err := openstack.NewIdentityV3(client, gophercloud.EndpointOpts{})
+	if err != nil {
+		return fmt.Errorf("error building identity client for token cache: %s", err)
+	}
+
+	result := tokens.Get(identity, client.TokenID)
+	token, err := result.ExtractToken()
+	if err != nil {
+		return fmt.Errorf("error reading token for cache: %s", err)
+	}
+	catalog, err := result.ExtractServiceCatalog()
+	if err != nil {
+		return fmt.Errorf("error reading service catalog for cache: %s", err)
+	}
+
+	cached := cachedToken{
+		TokenID:   client.TokenID,
+		Catalog:   catalog.Entries,
+		ExpiresAt: token.ExpiresAt,
+	}
+
+	raw, err := json.Marshal(&cached)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return fmt.Errorf("error creating token cache directory: %s", err)
+	}
+
+	return ioutil.WriteFile(path, raw, 0600)
+}
@@ -0,0 +1,132 @@
+package openstack
+
+import (
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/gophercloud/gophercloud"
+)
+
+func TestTokenCachePath(t *testing.T) {
+	var c AccessConfig
+
+	ao1 := &gophercloud.AuthOptions{IdentityEndpoint: "https://example.com/v3", Username: "alice", TenantName: "demo"}
+	ao2 := &gophercloud.AuthOptions{IdentityEndpoint: "https://example.com/v3", Username: "bob", TenantName: "demo"}
+	ao3 := &gophercloud.AuthOptions{IdentityEndpoint: "https://example.com/v3", ApplicationCredentialID: "cred-a"}
+	ao4 := &gophercloud.AuthOptions{IdentityEndpoint: "https://example.com/v3", ApplicationCredentialID: "cred-b"}
+
+	p1a, err := c.tokenCachePath(ao1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p1b, err := c.tokenCachePath(ao1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p1a != p1b {
+		t.Errorf("same AuthOptions produced different paths: %q vs %q", p1a, p1b)
+	}
+
+	p2, err := c.tokenCachePath(ao2)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p1a == p2 {
+		t.Errorf("different users hashed to the same path: %q", p1a)
+	}
+
+	p3, err := c.tokenCachePath(ao3)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	p4, err := c.tokenCachePath(ao4)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p3 == p4 {
+		t.Errorf("different application credentials hashed to the same path: %q", p3)
+	}
+
+	overridden := AccessConfig{TokenCachePath: "/tmp/custom-path.json"}
+	p, err := overridden.tokenCachePath(ao1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p != "/tmp/custom-path.json" {
+		t.Errorf("expected the override path to be used verbatim, got %q", p)
+	}
+
+	disabled := AccessConfig{TokenCachePath: "-"}
+	p, err = disabled.tokenCachePath(ao1)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if p != "" {
+		t.Errorf("expected caching to be disabled, got path %q", p)
+	}
+}
+
+func TestLoadCachedToken(t *testing.T) {
+	dir, err := ioutil.TempDir("", "packer-openstack-token-cache")
+	if err != nil {
+		t.Fatalf("error creating temp dir: %s", err)
+	}
+	defer os.RemoveAll(dir)
+
+	ao := &gophercloud.AuthOptions{IdentityEndpoint: "https://example.com/v3", Username: "alice"}
+
+	t.Run("missing file", func(t *testing.T) {
+		c := AccessConfig{TokenCachePath: filepath.Join(dir, "missing.json")}
+		cached, err := c.loadCachedToken(ao)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if cached != nil {
+			t.Fatalf("expected no cached token, got %+v", cached)
+		}
+	})
+
+	t.Run("expired token", func(t *testing.T) {
+		path := filepath.Join(dir, "expired.json")
+		writeCachedToken(t, path, cachedToken{TokenID: "stale", ExpiresAt: time.Now().Add(-time.Hour)})
+
+		c := AccessConfig{TokenCachePath: path}
+		cached, err := c.loadCachedToken(ao)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if cached != nil {
+			t.Fatalf("expected an expired token to be treated as a cache miss, got %+v", cached)
+		}
+	})
+
+	t.Run("valid token", func(t *testing.T) {
+		path := filepath.Join(dir, "valid.json")
+		writeCachedToken(t, path, cachedToken{TokenID: "fresh", ExpiresAt: time.Now().Add(time.Hour)})
+
+		c := AccessConfig{TokenCachePath: path}
+		cached, err := c.loadCachedToken(ao)
+		if err != nil {
+			t.Fatalf("unexpected error: %s", err)
+		}
+		if cached == nil || cached.TokenID != "fresh" {
+			t.Fatalf("expected to load the cached token, got %+v", cached)
+		}
+	})
+}
+
+func writeCachedToken(t *testing.T, path string, tok cachedToken) {
+	t.Helper()
+
+	raw, err := json.Marshal(&tok)
+	if err != nil {
+		t.Fatalf("error marshaling cached token: %s", err)
+	}
+	if err := ioutil.WriteFile(path, raw, 0600); err != nil {
+		t.Fatalf("error writing cached token: %s", err)
+	}
+}
@@ -0,0 +1,196 @@
+package openstack
+
+import (
+	"log"
+	"math/rand"
+	"net/http"
+	"net/http/httputil"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+
+	"golang.org/x/time/rate"
+)
+
+// defaultMaxRetries and defaultRetryMaxWait bound the exponential backoff
+// applied to transient errors when the user hasn't overridden max_retries /
+// retry_max_wait_seconds.
+const (
+	defaultMaxRetries   = 5
+	defaultRetryMaxWait = 30 * time.Second
+)
+
+// retryTransport retries requests that fail with a transient error
+// (429/502/503/504 or a connection-level error) using exponential backoff,
+// honoring a server-supplied Retry-After header when present.
+type retryTransport struct {
+	next       http.RoundTripper
+	maxRetries int
+	maxWait    time.Duration
+}
+
+func (t *retryTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var resp *http.Response
+	var err error
+
+	for attempt := 0; ; attempt++ {
+		// req.Body was already drained by the previous attempt; rewind it
+		// from GetBody before retrying, or give up if it can't be rewound.
+		if attempt > 0 && req.Body != nil && req.Body != http.NoBody {
+			if req.GetBody == nil {
+				return resp, err
+			}
+			body, bodyErr := req.GetBody()
+			if bodyErr != nil {
+				return resp, bodyErr
+			}
+			req.Body = body
+		}
+
+		resp, err = t.next.RoundTrip(req)
+
+		if err == nil && !isRetryableStatus(resp.StatusCode) {
+			return resp, nil
+		}
+		if attempt >= t.maxRetries {
+			return resp, err
+		}
+
+		wait := retryAfter(resp)
+		if wait == 0 {
+			wait = backoff(attempt)
+		}
+		if wait > t.maxWait {
+			wait = t.maxWait
+		}
+
+		if resp != nil {
+			resp.Body.Close()
+		}
+
+		time.Sleep(wait)
+	}
+}
+
+func isRetryableStatus(code int) bool {
+	switch code {
+	case http.StatusTooManyRequests, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// retryAfter returns the server-requested backoff from a Retry-After
+// header, or zero if absent/unparseable.
+func retryAfter(resp *http.Response) time.Duration {
+	if resp == nil {
+		return 0
+	}
+	v := resp.Header.Get("Retry-After")
+	if v == "" {
+		return 0
+	}
+	if seconds, err := strconv.Atoi(v); err == nil {
+		return time.Duration(seconds) * time.Second
+	}
+	if t, err := http.ParseTime(v); err == nil {
+		return time.Until(t)
+	}
+	return 0
+}
+
+// backoff returns an exponential delay with jitter for the given (zero
+// indexed) retry attempt.
+func backoff(attempt int) time.Duration {
+	base := time.Duration(1<<uint(attempt)) * time.Second
+	jitter := time.Duration(rand.Int63n(int64(time.Second)))
+	return base + jitter
+}
+
+// rateLimitTransport throttles outgoing requests to maintain at most a
+// fixed number of requests per second against a single Keystone/Nova
+// endpoint.
+type rateLimitTransport struct {
+	next    http.RoundTripper
+	limiter *rate.Limiter
+}
+
+func (t *rateLimitTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.limiter.Wait(req.Context()); err != nil {
+		return nil, err
+	}
+	return t.next.RoundTrip(req)
+}
+
+// loggingTransport logs each request and response when PACKER_LOG is set,
+// redacting the auth token header and any password fields in the body.
+type loggingTransport struct {
+	next http.RoundTripper
+}
+
+var redactFieldRe = regexp.MustCompile(`(?i)("password"\s*:\s*)"[^"]*"`)
+
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if dump, err := httputil.DumpRequestOut(req, true); err == nil {
+		log.Printf("[DEBUG] OpenStack API request:\n%s", redact(dump))
+	}
+
+	resp, err := t.next.RoundTrip(req)
+	if err != nil {
+		return resp, err
+	}
+
+	if dump, err := httputil.DumpResponse(resp, true); err == nil {
+		log.Printf("[DEBUG] OpenStack API response:\n%s", redact(dump))
+	}
+
+	return resp, err
+}
+
+func redact(dump []byte) []byte {
+	s := string(dump)
+	s = redactHeader(s, "X-Auth-Token")
+	s = redactHeader(s, "X-Subject-Token")
+	s = redactFieldRe.ReplaceAllString(s, `${1}"***"`)
+	return []byte(s)
+}
+
+func redactHeader(s, header string) string {
+	re := regexp.MustCompile(`(?i)(` + regexp.QuoteMeta(header) + `:\s*).*`)
+	return re.ReplaceAllString(s, "${1}***")
+}
+
+// wrapTransport layers the rate-limit, logging and retry middleware
+// configured on c around base, innermost (rate-limit) to outermost
+// (retry), so that every retried attempt is itself rate-limited and
+// logged rather than only the first one.
+func (c *AccessConfig) wrapTransport(base http.RoundTripper) http.RoundTripper {
+	rt := base
+
+	if c.RateLimit > 0 {
+		burst := int(c.RateLimit)
+		if burst < 1 {
+			burst = 1
+		}
+		rt = &rateLimitTransport{next: rt, limiter: rate.NewLimiter(rate.Limit(c.RateLimit), burst)}
+	}
+
+	if os.Getenv("PACKER_LOG") != "" {
+		rt = &loggingTransport{next: rt}
+	}
+
+	maxRetries := c.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultMaxRetries
+	}
+	maxWait := defaultRetryMaxWait
+	if c.RetryMaxWaitSeconds > 0 {
+		maxWait = time.Duration(c.RetryMaxWaitSeconds) * time.Second
+	}
+	rt = &retryTransport{next: rt, maxRetries: maxRetries, maxWait: maxWait}
+
+	return rt
+}
@@ -0,0 +1,74 @@
+package openstack
+
+import (
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestIsRetryableStatus(t *testing.T) {
+	cases := map[int]bool{
+		http.StatusOK:                 false,
+		http.StatusNotFound:           false,
+		http.StatusUnauthorized:       false,
+		http.StatusTooManyRequests:    true,
+		http.StatusBadGateway:         true,
+		http.StatusServiceUnavailable: true,
+		http.StatusGatewayTimeout:     true,
+	}
+
+	for status, want := range cases {
+		if got := isRetryableStatus(status); got != want {
+			t.Errorf("isRetryableStatus(%d) = %v, want %v", status, got, want)
+		}
+	}
+}
+
+func TestRetryAfter(t *testing.T) {
+	t.Run("nil response", func(t *testing.T) {
+		if got := retryAfter(nil); got != 0 {
+			t.Errorf("expected 0, got %s", got)
+		}
+	})
+
+	t.Run("no header", func(t *testing.T) {
+		resp := &http.Response{Header: http.Header{}}
+		if got := retryAfter(resp); got != 0 {
+			t.Errorf("expected 0, got %s", got)
+		}
+	})
+
+	t.Run("seconds", func(t *testing.T) {
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{"5"}}}
+		if got, want := retryAfter(resp), 5*time.Second; got != want {
+			t.Errorf("got %s, want %s", got, want)
+		}
+	})
+
+	t.Run("http-date", func(t *testing.T) {
+		future := time.Now().Add(10 * time.Second).UTC()
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{future.Format(http.TimeFormat)}}}
+		got := retryAfter(resp)
+		if got <= 0 || got > 11*time.Second {
+			t.Errorf("got %s, want something close to 10s", got)
+		}
+	})
+
+	t.Run("unparseable", func(t *testing.T) {
+		resp := &http.Response{Header: http.Header{"Retry-After": []string{"not-a-value"}}}
+		if got := retryAfter(resp); got != 0 {
+			t.Errorf("expected 0, got %s", got)
+		}
+	})
+}
+
+func TestBackoff(t *testing.T) {
+	for attempt := 0; attempt < 5; attempt++ {
+		wait := backoff(attempt)
+		min := time.Duration(1<<uint(attempt)) * time.Second
+		max := min + time.Second
+		if wait < min || wait > max {
+			t.Errorf("backoff(%d) = %s, want between %s and %s", attempt, wait, min, max)
+		}
+	}
+}
@@ -0,0 +1,15 @@
+package main
+
+import (
+	"github.com/hashicorp/packer/packer/plugin"
+	swift "github.com/hashicorp/packer/postprocessor/openstack-swift"
+)
+
+func main() {
+	server, err := plugin.Server()
+	if err != nil {
+		panic(err)
+	}
+	server.RegisterPostProcessor(new(swift.PostProcessor))
+	server.Serve()
+}
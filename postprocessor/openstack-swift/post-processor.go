@@ -0,0 +1,168 @@
+package swift
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"github.com/gophercloud/gophercloud"
+	"github.com/gophercloud/gophercloud/openstack/objectstorage/v1/containers"
+	"github.com/gophercloud/gophercloud/openstack/objectstorage/v1/objects"
+	"github.com/hashicorp/packer/builder/openstack"
+	"github.com/hashicorp/packer/common"
+	"github.com/hashicorp/packer/helper/config"
+	"github.com/hashicorp/packer/packer"
+	"github.com/hashicorp/packer/template/interpolate"
+)
+
+// defaultSegmentSize is used when SegmentSize is left at zero. 1GiB sits
+// comfortably under the default Swift proxy max file size of 5GiB.
+const defaultSegmentSize int64 = 1 << 30
+
+type Config struct {
+	common.PackerConfig    `mapstructure:",squash"`
+	openstack.AccessConfig `mapstructure:",squash"`
+
+	// Container is the Swift container the artifact files are uploaded to.
+	Container string `mapstructure:"container"`
+	// ObjectName overrides the uploaded object's name; it defaults to the
+	// artifact file's own base name. Only valid for single-file artifacts.
+	ObjectName string `mapstructure:"object_name"`
+	// SegmentSize is the size, in bytes, that each segment of a large
+	// object upload is split into. Defaults to 1GiB. Files smaller than
+	// SegmentSize are uploaded as a single object.
+	SegmentSize int64 `mapstructure:"segment_size"`
+
+	ctx interpolate.Context
+}
+
+type PostProcessor struct {
+	config Config
+}
+
+func (p *PostProcessor) Configure(raws ...interface{}) error {
+	err := config.Decode(&p.config, &config.DecodeOpts{
+		Interpolate:        true,
+		InterpolateContext: &p.config.ctx,
+	}, raws...)
+	if err != nil {
+		return err
+	}
+
+	errs := new(packer.MultiError)
+
+	if p.config.Container == "" {
+		errs = packer.MultiErrorAppend(errs, fmt.Errorf("container must be specified"))
+	}
+	if p.config.SegmentSize == 0 {
+		p.config.SegmentSize = defaultSegmentSize
+	}
+
+	if es := p.config.AccessConfig.Prepare(&p.config.ctx); len(es) > 0 {
+		errs = packer.MultiErrorAppend(errs, es...)
+	}
+
+	if len(errs.Errors) > 0 {
+		return errs
+	}
+	return nil
+}
+
+func (p *PostProcessor) PostProcess(ui packer.Ui, artifact packer.Artifact) (packer.Artifact, bool, bool, error) {
+	client, err := p.config.AccessConfig.ObjectStorageV1Client()
+	if err != nil {
+		return nil, false, false, fmt.Errorf("error creating Swift client: %s", err)
+	}
+
+	files := artifact.Files()
+	if len(files) == 0 {
+		return nil, false, false, fmt.Errorf("no files found in artifact")
+	}
+	if len(files) > 1 && p.config.ObjectName != "" {
+		return nil, false, false, fmt.Errorf("object_name can only be used with single-file artifacts")
+	}
+
+	if _, err := containers.Create(client, p.config.Container, nil).Extract(); err != nil {
+		return nil, false, false, fmt.Errorf("error creating container %q: %s", p.config.Container, err)
+	}
+
+	for _, path := range files {
+		objectName := p.config.ObjectName
+		if objectName == "" {
+			objectName = filepath.Base(path)
+		}
+
+		ui.Say(fmt.Sprintf("Uploading %s to Swift container %q as %q", path, p.config.Container, objectName))
+
+		if err := p.uploadObject(client, path, objectName); err != nil {
+			return nil, false, false, fmt.Errorf("error uploading %s: %s", path, err)
+		}
+	}
+
+	return artifact, true, false, nil
+}
+
+// uploadObject uploads path as objectName, transparently splitting it into
+// SegmentSize segments and stitching them back together as a dynamic large
+// object when the file is bigger than a single segment.
+func (p *PostProcessor) uploadObject(client *gophercloud.ServiceClient, path string, objectName string) error {
+	f, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+
+	if info.Size() <= p.config.SegmentSize {
+		return objects.Create(client, p.config.Container, objectName, objects.CreateOpts{
+			Content: f,
+		}).Err
+	}
+
+	return p.uploadLargeObject(client, f, objectName)
+}
+
+// uploadLargeObject uploads r in SegmentSize chunks to "<container>_segments"
+// and then creates a zero-byte manifest object at objectName whose
+// X-Object-Manifest header stitches the segments into a single dynamic
+// large object.
+func (p *PostProcessor) uploadLargeObject(client *gophercloud.ServiceClient, r io.Reader, objectName string) error {
+	segmentsContainer := p.config.Container + "_segments"
+	if _, err := containers.Create(client, segmentsContainer, nil).Extract(); err != nil {
+		return fmt.Errorf("error creating segments container %q: %s", segmentsContainer, err)
+	}
+
+	segmentPrefix := fmt.Sprintf("%s/", objectName)
+	for segmentIndex := 0; ; segmentIndex++ {
+		var buf bytes.Buffer
+		n, err := io.CopyN(&buf, r, p.config.SegmentSize)
+		if err != nil && err != io.EOF {
+			return err
+		}
+		if n == 0 {
+			break
+		}
+
+		segmentName := fmt.Sprintf("%s%08d", segmentPrefix, segmentIndex)
+		if err := objects.Create(client, segmentsContainer, segmentName, objects.CreateOpts{
+			Content: &buf,
+		}).Err; err != nil {
+			return fmt.Errorf("error uploading segment %d: %s", segmentIndex, err)
+		}
+
+		if n < p.config.SegmentSize {
+			break
+		}
+	}
+
+	return objects.Create(client, p.config.Container, objectName, objects.CreateOpts{
+		Content:        bytes.NewReader(nil),
+		ObjectManifest: segmentsContainer + "/" + segmentPrefix,
+	}).Err
+}